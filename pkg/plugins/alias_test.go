@@ -0,0 +1,23 @@
+package plugins
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAlias(t *testing.T) {
+	installed := []*Plugin{
+		{JSONData: JSONData{ID: "prometheus"}},
+		{JSONData: JSONData{ID: "prometheus-preview"}, Alias: "prom-staging"},
+	}
+
+	require.NoError(t, ValidateAlias("", installed))
+	require.NoError(t, ValidateAlias("prom-canary", installed))
+
+	err := ValidateAlias("prometheus", installed)
+	require.ErrorAs(t, err, &ErrAliasAlreadyExists{})
+
+	err = ValidateAlias("prom-staging", installed)
+	require.ErrorAs(t, err, &ErrAliasAlreadyExists{})
+}
@@ -0,0 +1,444 @@
+package backendplugin
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Frame flags. A frame carries exactly one of these; a WindowUpdate is
+// always sent in a frame of its own, with a 4-byte increment as payload.
+const (
+	FlagOpen byte = 1 << iota
+	FlagData
+	FlagCloseWrite
+	FlagClose
+	FlagWindowUpdate
+)
+
+// controlStreamID is reserved for session-level control messages (ping,
+// goaway, error) and is never used for a logical request/response stream.
+const controlStreamID uint32 = 0
+
+// frameHeaderSize is the wire size, in bytes, of a frame header: streamID
+// (4) + flags (1) + length (4).
+const frameHeaderSize = 4 + 1 + 4
+
+// DefaultWindowSize is the initial per-stream send window, in bytes: how
+// much a Stream may write before the peer grants more. Each stream's window
+// is tracked on its own; a Session has no connection-wide window.
+const DefaultWindowSize = 256 * 1024
+
+// frame is the wire format carried over the muxed connection:
+// {streamID uint32, flags uint8, length uint32, payload []byte}.
+type frame struct {
+	streamID uint32
+	flags    byte
+	payload  []byte
+}
+
+func writeFrame(w io.Writer, f frame) error {
+	hdr := make([]byte, frameHeaderSize)
+	binary.BigEndian.PutUint32(hdr[0:4], f.streamID)
+	hdr[4] = f.flags
+	binary.BigEndian.PutUint32(hdr[5:9], uint32(len(f.payload)))
+	if _, err := w.Write(hdr); err != nil {
+		return err
+	}
+	if len(f.payload) == 0 {
+		return nil
+	}
+	_, err := w.Write(f.payload)
+	return err
+}
+
+func readFrame(r io.Reader) (frame, error) {
+	hdr := make([]byte, frameHeaderSize)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return frame{}, err
+	}
+	f := frame{
+		streamID: binary.BigEndian.Uint32(hdr[0:4]),
+		flags:    hdr[4],
+	}
+	length := binary.BigEndian.Uint32(hdr[5:9])
+	if length > 0 {
+		f.payload = make([]byte, length)
+		if _, err := io.ReadFull(r, f.payload); err != nil {
+			return frame{}, err
+		}
+	}
+	return f, nil
+}
+
+// ErrSessionClosed is returned by Session and stream operations once the
+// underlying connection has gone away.
+var ErrSessionClosed = errors.New("backendplugin: mux session closed")
+
+// Session multiplexes many logical, independently flow-controlled streams
+// over a single underlying connection (a plugin's stdio pipe or gRPC bidi
+// stream), so a long-running CallResource or RunStream call can't be
+// head-of-line-blocked behind a large QueryData response.
+//
+// Grafana opens streams with odd IDs; the plugin opens streams with even
+// IDs, so either side can initiate -- needed for RunStream pushes from the
+// plugin. Stream ID 0 is reserved for session control.
+type Session struct {
+	conn   io.ReadWriteCloser
+	client bool // true if this Session opens odd-numbered streams
+
+	maxConcurrentStreams int
+	windowSize           int
+
+	mu        sync.Mutex
+	streams   map[uint32]*Stream
+	nextLocal uint32
+	closed    bool
+
+	// writeMu serializes writes to conn. It is deliberately separate from mu:
+	// conn.Write can block (the peer may not be reading), and holding mu for
+	// that long would stall dispatch's map lookups for every other stream on
+	// this session behind a single slow write.
+	writeMu sync.Mutex
+
+	accept chan *Stream
+	werr   chan error
+}
+
+// NewSession wraps conn in a multiplexed Session. isClient determines
+// whether locally-opened streams get odd (true, Grafana-initiated) or even
+// (false, plugin-initiated) IDs.
+func NewSession(conn io.ReadWriteCloser, isClient bool, maxConcurrentStreams int) *Session {
+	if maxConcurrentStreams <= 0 {
+		maxConcurrentStreams = 128
+	}
+	s := &Session{
+		conn:                 conn,
+		client:               isClient,
+		maxConcurrentStreams: maxConcurrentStreams,
+		windowSize:           DefaultWindowSize,
+		streams:              make(map[uint32]*Stream),
+		accept:               make(chan *Stream, maxConcurrentStreams),
+		werr:                 make(chan error, 1),
+	}
+	if isClient {
+		s.nextLocal = 1
+	} else {
+		s.nextLocal = 2
+	}
+	go s.readLoop()
+	return s
+}
+
+// Open starts a new logical stream and returns a net.Conn-like handle for
+// it. The peer observes the stream via Accept.
+func (s *Session) Open() (*Stream, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, ErrSessionClosed
+	}
+	if len(s.streams) >= s.maxConcurrentStreams {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("backendplugin: max concurrent streams (%d) reached", s.maxConcurrentStreams)
+	}
+	id := s.nextLocal
+	s.nextLocal += 2
+	st := newStream(id, s)
+	s.streams[id] = st
+	s.mu.Unlock()
+
+	if err := s.writeFrame(frame{streamID: id, flags: FlagOpen}); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// Accept blocks until the peer opens a new logical stream, or the session
+// closes.
+func (s *Session) Accept() (*Stream, error) {
+	st, ok := <-s.accept
+	if !ok {
+		return nil, ErrSessionClosed
+	}
+	return st, nil
+}
+
+// Close tears down the session and all open streams.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	for _, st := range s.streams {
+		st.closeLocally(ErrSessionClosed)
+	}
+	close(s.accept)
+	s.mu.Unlock()
+	return s.conn.Close()
+}
+
+func (s *Session) writeFrame(f frame) error {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return ErrSessionClosed
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return writeFrame(s.conn, f)
+}
+
+func (s *Session) readLoop() {
+	for {
+		f, err := readFrame(s.conn)
+		if err != nil {
+			s.teardown(err)
+			return
+		}
+		if f.streamID == controlStreamID {
+			// Control frames (ping, goaway, error) are read and discarded:
+			// no control message is acted on, and none is fatal, so a peer
+			// can send control traffic without breaking this side.
+			continue
+		}
+		s.dispatch(f)
+	}
+}
+
+func (s *Session) dispatch(f frame) {
+	s.mu.Lock()
+	st, ok := s.streams[f.streamID]
+	if !ok {
+		if f.flags&FlagOpen == 0 {
+			s.mu.Unlock()
+			return
+		}
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+		if len(s.streams) >= s.maxConcurrentStreams {
+			s.mu.Unlock()
+			// Reject without blocking the read loop: a peer that opens more
+			// streams than we'll admit must not be able to stall control-frame
+			// processing (or every other stream) behind a full accept queue.
+			_ = s.writeFrame(frame{streamID: f.streamID, flags: FlagClose})
+			return
+		}
+		st = newStream(f.streamID, s)
+		s.streams[f.streamID] = st
+		// Send while still holding s.mu so this can never race a concurrent
+		// Close/teardown closing s.accept out from under us.
+		s.accept <- st
+		s.mu.Unlock()
+	} else {
+		s.mu.Unlock()
+	}
+
+	switch {
+	case f.flags&FlagData != 0:
+		st.acceptData(f.payload)
+	case f.flags&FlagCloseWrite != 0:
+		st.acceptCloseWrite()
+	case f.flags&FlagClose != 0:
+		s.mu.Lock()
+		delete(s.streams, f.streamID)
+		s.mu.Unlock()
+		st.closeLocally(io.EOF)
+	case f.flags&FlagWindowUpdate != 0 && len(f.payload) == 4:
+		st.grantWindow(binary.BigEndian.Uint32(f.payload))
+	}
+}
+
+func (s *Session) teardown(err error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	for _, st := range s.streams {
+		st.closeLocally(err)
+	}
+	close(s.accept)
+	s.mu.Unlock()
+}
+
+func (s *Session) removeStream(id uint32) {
+	s.mu.Lock()
+	delete(s.streams, id)
+	s.mu.Unlock()
+}
+
+// Stream is one logical, flow-controlled, bidirectional channel within a
+// Session. It implements a net.Conn-like surface so callers of
+// CallResource/RunStream can treat each request as an isolated connection.
+type Stream struct {
+	id      uint32
+	session *Session
+
+	mu          sync.Mutex
+	buf         []byte
+	sendWindow  int
+	readClosed  bool
+	closed      bool
+	closeErr    error
+	readReady   chan struct{}
+	windowReady chan struct{}
+}
+
+func newStream(id uint32, s *Session) *Stream {
+	return &Stream{
+		id:          id,
+		session:     s,
+		sendWindow:  s.windowSize,
+		readReady:   make(chan struct{}, 1),
+		windowReady: make(chan struct{}, 1),
+	}
+}
+
+func (st *Stream) acceptData(p []byte) {
+	st.mu.Lock()
+	st.buf = append(st.buf, p...)
+	st.mu.Unlock()
+	select {
+	case st.readReady <- struct{}{}:
+	default:
+	}
+}
+
+func (st *Stream) acceptCloseWrite() {
+	st.mu.Lock()
+	st.readClosed = true
+	st.mu.Unlock()
+	select {
+	case st.readReady <- struct{}{}:
+	default:
+	}
+}
+
+// grantWindow applies a WINDOW_UPDATE received from the peer, topping up how
+// much more this side may send, and wakes any Write blocked on the window
+// being exhausted.
+func (st *Stream) grantWindow(n uint32) {
+	st.mu.Lock()
+	st.sendWindow += int(n)
+	st.mu.Unlock()
+	select {
+	case st.windowReady <- struct{}{}:
+	default:
+	}
+}
+
+// grantReadWindow tells the peer it may send n more bytes, reflecting that
+// Read just freed n bytes from this stream's receive buffer. Without this,
+// a stream's one-shot initial window would cap it to DefaultWindowSize for
+// its entire lifetime.
+func (st *Stream) grantReadWindow(n int) {
+	if n <= 0 {
+		return
+	}
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint32(payload, uint32(n))
+	_ = st.session.writeFrame(frame{streamID: st.id, flags: FlagWindowUpdate, payload: payload})
+}
+
+func (st *Stream) closeLocally(err error) {
+	st.mu.Lock()
+	if st.closed {
+		st.mu.Unlock()
+		return
+	}
+	st.closed = true
+	st.closeErr = err
+	st.mu.Unlock()
+	select {
+	case st.readReady <- struct{}{}:
+	default:
+	}
+	select {
+	case st.windowReady <- struct{}{}:
+	default:
+	}
+}
+
+// Read implements io.Reader, blocking until data, a peer close-write, or
+// session teardown.
+func (st *Stream) Read(p []byte) (int, error) {
+	for {
+		st.mu.Lock()
+		if len(st.buf) > 0 {
+			n := copy(p, st.buf)
+			st.buf = st.buf[n:]
+			st.mu.Unlock()
+			st.grantReadWindow(n)
+			return n, nil
+		}
+		if st.readClosed {
+			st.mu.Unlock()
+			return 0, io.EOF
+		}
+		if st.closed {
+			err := st.closeErr
+			st.mu.Unlock()
+			if err == nil {
+				err = io.EOF
+			}
+			return 0, err
+		}
+		st.mu.Unlock()
+		<-st.readReady
+	}
+}
+
+// Write implements io.Writer, sending DATA frames and respecting the
+// stream's flow-control window so one oversized write can't starve other
+// streams or the control channel. Once the window is exhausted, Write blocks
+// until the peer's Read grants more of it via a WINDOW_UPDATE frame.
+func (st *Stream) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		st.mu.Lock()
+		for st.sendWindow <= 0 && !st.closed {
+			st.mu.Unlock()
+			<-st.windowReady
+			st.mu.Lock()
+		}
+		if st.closed {
+			st.mu.Unlock()
+			return written, ErrSessionClosed
+		}
+		chunk := len(p) - written
+		if chunk > st.sendWindow {
+			chunk = st.sendWindow
+		}
+		st.sendWindow -= chunk
+		st.mu.Unlock()
+
+		if err := st.session.writeFrame(frame{streamID: st.id, flags: FlagData, payload: p[written : written+chunk]}); err != nil {
+			return written, err
+		}
+		written += chunk
+	}
+	return written, nil
+}
+
+// CloseWrite half-closes the stream: the peer will observe io.EOF on Read
+// but this side can still read the peer's response.
+func (st *Stream) CloseWrite() error {
+	return st.session.writeFrame(frame{streamID: st.id, flags: FlagCloseWrite})
+}
+
+// Close fully closes the stream and notifies the peer.
+func (st *Stream) Close() error {
+	st.closeLocally(io.EOF)
+	st.session.removeStream(st.id)
+	return st.session.writeFrame(frame{streamID: st.id, flags: FlagClose})
+}
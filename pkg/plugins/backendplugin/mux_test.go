@@ -0,0 +1,46 @@
+package backendplugin
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	cases := []frame{
+		{streamID: 0, flags: FlagOpen, payload: nil},
+		{streamID: 7, flags: FlagData, payload: []byte("hello, plugin")},
+		{streamID: 4, flags: FlagWindowUpdate, payload: []byte{0, 0, 1, 0}},
+	}
+
+	for _, want := range cases {
+		var buf bytes.Buffer
+		require.NoError(t, writeFrame(&buf, want))
+
+		got, err := readFrame(&buf)
+		require.NoError(t, err)
+		require.Equal(t, want.streamID, got.streamID)
+		require.Equal(t, want.flags, got.flags)
+		require.Equal(t, want.payload, got.payload)
+	}
+}
+
+func TestFrameRoundTripMultipleFrames(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeFrame(&buf, frame{streamID: 1, flags: FlagOpen}))
+	require.NoError(t, writeFrame(&buf, frame{streamID: 1, flags: FlagData, payload: []byte("payload")}))
+	require.NoError(t, writeFrame(&buf, frame{streamID: 1, flags: FlagClose}))
+
+	first, err := readFrame(&buf)
+	require.NoError(t, err)
+	require.Equal(t, FlagOpen, first.flags)
+
+	second, err := readFrame(&buf)
+	require.NoError(t, err)
+	require.Equal(t, []byte("payload"), second.payload)
+
+	third, err := readFrame(&buf)
+	require.NoError(t, err)
+	require.Equal(t, FlagClose, third.flags)
+}
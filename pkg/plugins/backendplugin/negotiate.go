@@ -0,0 +1,42 @@
+package backendplugin
+
+import "io"
+
+// muxProtocolHandshake is written by the mux-capable side of a connection
+// immediately after it is established. A plugin that doesn't understand it
+// will either fail to parse it (and the connection is torn down by the
+// go-plugin health check, as today) or, for plugins built against an SDK
+// that knows to ignore unrecognized preambles, simply not echo it back.
+const muxProtocolHandshake = "grafana-plugin-mux/1\n"
+
+// NegotiateMux writes the mux handshake preamble on conn and waits for the
+// peer to echo it back within the handshake window. If the peer echoes it,
+// the connection is wrapped in a Session and mux framing is used. If the
+// peer doesn't support muxing, the shim falls back to legacy single-stream
+// behavior by returning a nil Session and letting the caller drive conn
+// directly, unmodified. With isClient false the roles are reversed: it
+// waits for the peer's preamble first and echoes it back.
+func NegotiateMux(conn io.ReadWriteCloser, isClient bool, maxConcurrentStreams int) (*Session, error) {
+	if isClient {
+		if _, err := io.WriteString(conn, muxProtocolHandshake); err != nil {
+			return nil, err
+		}
+	}
+
+	echo := make([]byte, len(muxProtocolHandshake))
+	n, err := io.ReadFull(conn, echo)
+	if err != nil || n != len(echo) || string(echo) != muxProtocolHandshake {
+		// Peer doesn't speak the mux protocol (or the read failed outright);
+		// negotiate down to the existing single-stream behavior rather than
+		// failing the connection.
+		return nil, nil
+	}
+
+	if !isClient {
+		if _, err := io.WriteString(conn, muxProtocolHandshake); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewSession(conn, isClient, maxConcurrentStreams), nil
+}
@@ -0,0 +1,35 @@
+package backendplugin
+
+import "io"
+
+// StdioTransport adapts a plugin subprocess's stdin/stdout pipes into the
+// single io.ReadWriteCloser NegotiateMux expects, so a plugin client talking
+// to the process over stdio can multiplex CallResource/RunStream streams
+// over the same pipes it already uses for unary RPCs. A plugin client embeds
+// or returns a StdioTransport from its MuxTransport method to opt into
+// muxing; clients that don't (e.g. ones talking over a transport that can't
+// be safely interleaved) are left on the existing single-stream behavior.
+type StdioTransport struct {
+	Stdout io.ReadCloser
+	Stdin  io.WriteCloser
+}
+
+func (t StdioTransport) Read(p []byte) (int, error) { return t.Stdout.Read(p) }
+
+func (t StdioTransport) Write(p []byte) (int, error) { return t.Stdin.Write(p) }
+
+// Close closes both pipes, returning the first error encountered.
+func (t StdioTransport) Close() error {
+	writeErr := t.Stdin.Close()
+	readErr := t.Stdout.Close()
+	if writeErr != nil {
+		return writeErr
+	}
+	return readErr
+}
+
+// MuxTransport implements the muxTransport interface plugins.Plugin expects
+// from its registered client.
+func (t StdioTransport) MuxTransport() io.ReadWriteCloser {
+	return t
+}
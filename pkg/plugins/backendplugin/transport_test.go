@@ -0,0 +1,32 @@
+package backendplugin
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStdioTransportRoundTrip(t *testing.T) {
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+	defer func() { _ = stdinW.Close(); _ = stdoutW.Close() }()
+
+	transport := StdioTransport{Stdout: stdoutR, Stdin: stdinW}
+
+	go func() {
+		buf := make([]byte, 5)
+		_, _ = io.ReadFull(stdinR, buf)
+		_, _ = stdoutW.Write(buf)
+	}()
+
+	_, err := transport.Write([]byte("hello"))
+	require.NoError(t, err)
+
+	got := make([]byte, 5)
+	_, err = io.ReadFull(transport, got)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(got))
+
+	require.Equal(t, io.ReadWriteCloser(transport), transport.MuxTransport())
+}
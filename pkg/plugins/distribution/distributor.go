@@ -0,0 +1,258 @@
+package distribution
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+// AuthConfig carries the credentials used to authenticate against a
+// registry's /v2/ API, mirroring the docker registry auth model.
+type AuthConfig struct {
+	Username string
+	Password string
+
+	// Token is used as a bearer token instead of Username/Password when set.
+	Token string
+}
+
+func (a AuthConfig) setAuth(req *http.Request) {
+	switch {
+	case a.Token != "":
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	case a.Username != "":
+		req.SetBasicAuth(a.Username, a.Password)
+	}
+}
+
+// Ref identifies a plugin image in a registry, e.g.
+// "registry.example.com/grafana-plugins/my-datasource:1.2.3".
+type Ref struct {
+	Registry   string
+	Repository string
+	Reference  string // tag or digest
+}
+
+// ParseRef parses s into a Ref.
+func ParseRef(s string) (Ref, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return Ref{}, fmt.Errorf("invalid ref %q: expected <registry>/<repository>[:<reference>]", s)
+	}
+	registry, rest := parts[0], parts[1]
+
+	repository, reference := rest, "latest"
+	if i := strings.LastIndex(rest, ":"); i != -1 && !strings.Contains(rest[i:], "/") {
+		repository, reference = rest[:i], rest[i+1:]
+	}
+	if repository == "" {
+		return Ref{}, fmt.Errorf("invalid ref %q: missing repository", s)
+	}
+
+	return Ref{Registry: registry, Repository: repository, Reference: reference}, nil
+}
+
+// PullResult is what a successful Pull returns: the verified manifest, the
+// exact bytes the registry served for it, and the plugin's JSONData
+// (including its declared privileges) decoded from the manifest's config
+// blob. ManifestBytes is kept alongside the decoded Manifest because it is
+// the registry's actual content -- re-marshaling Manifest can reorder or
+// drop fields a real registry response has (e.g. a top-level mediaType) and
+// would produce a digest that no longer matches what other OCI tooling
+// computes from the same manifest.
+type PullResult struct {
+	Manifest      Manifest
+	ManifestBytes []byte
+	Config        plugins.JSONData
+}
+
+// Distributor pulls and pushes plugins to and from an OCI-compatible
+// registry, as an alternative to the signed-zip distribution flow.
+type Distributor interface {
+	// Pull fetches the manifest for ref, verifies the config and layer
+	// digests, decodes the config blob, and extracts the layers into the
+	// distributor's destination directory.
+	Pull(ctx context.Context, ref Ref, auth AuthConfig) (PullResult, error)
+
+	// Push uploads manifest to ref. It does not upload the config or layer
+	// blobs the manifest names.
+	Push(ctx context.Context, ref Ref, manifest Manifest, auth AuthConfig) error
+}
+
+// HTTPDistributor is a Distributor backed by the standard OCI distribution
+// HTTP API (GET/PUT /v2/<name>/manifests/<ref>, /v2/<name>/blobs/<digest>).
+type HTTPDistributor struct {
+	Client *http.Client
+
+	// Dest is the directory that pulled layers are extracted into.
+	Dest string
+}
+
+// NewHTTPDistributor returns a Distributor that extracts pulled layers into
+// dest.
+func NewHTTPDistributor(dest string) *HTTPDistributor {
+	return &HTTPDistributor{Client: http.DefaultClient, Dest: dest}
+}
+
+func (d *HTTPDistributor) httpClient() *http.Client {
+	if d.Client != nil {
+		return d.Client
+	}
+	return http.DefaultClient
+}
+
+func (d *HTTPDistributor) Pull(ctx context.Context, ref Ref, auth AuthConfig) (PullResult, error) {
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", ref.Registry, ref.Repository, ref.Reference)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL, nil)
+	if err != nil {
+		return PullResult{}, fmt.Errorf("build manifest request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.oci.image.manifest.v1+json")
+	auth.setAuth(req)
+
+	resp, err := d.httpClient().Do(req)
+	if err != nil {
+		return PullResult{}, fmt.Errorf("fetch manifest: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return PullResult{}, fmt.Errorf("fetch manifest %s: unexpected status %d", ref.Repository, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return PullResult{}, fmt.Errorf("read manifest: %w", err)
+	}
+	manifest, err := UnmarshalManifest(body)
+	if err != nil {
+		return PullResult{}, err
+	}
+
+	configBlob, err := d.fetchBlob(ctx, ref, manifest.Config, auth)
+	if err != nil {
+		return PullResult{}, fmt.Errorf("fetch config blob: %w", err)
+	}
+	if !manifest.Config.Digest.Verify(configBlob) {
+		return PullResult{}, fmt.Errorf("config digest mismatch for %s: manifest declares %s", ref.Repository, manifest.Config.Digest)
+	}
+	var config plugins.JSONData
+	if err := json.Unmarshal(configBlob, &config); err != nil {
+		return PullResult{}, fmt.Errorf("decode config blob for %s: %w", ref.Repository, err)
+	}
+
+	for _, layer := range manifest.Layers {
+		blob, err := d.fetchBlob(ctx, ref, layer, auth)
+		if err != nil {
+			return PullResult{}, fmt.Errorf("fetch layer blob %s: %w", layer.Digest, err)
+		}
+		if !layer.Digest.Verify(blob) {
+			return PullResult{}, fmt.Errorf("layer digest mismatch for %s: manifest declares %s", ref.Repository, layer.Digest)
+		}
+		if err := extractTarGz(blob, d.Dest); err != nil {
+			return PullResult{}, fmt.Errorf("extract layer %s: %w", layer.Digest, err)
+		}
+	}
+
+	return PullResult{Manifest: manifest, ManifestBytes: body, Config: config}, nil
+}
+
+func (d *HTTPDistributor) fetchBlob(ctx context.Context, ref Ref, desc Descriptor, auth AuthConfig) ([]byte, error) {
+	blobURL := fmt.Sprintf("https://%s/v2/%s/blobs/%s", ref.Registry, ref.Repository, desc.Digest)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, blobURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	auth.setAuth(req)
+
+	resp, err := d.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (d *HTTPDistributor) Push(ctx context.Context, ref Ref, manifest Manifest, auth AuthConfig) error {
+	manifestBody, err := MarshalManifest(manifest)
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", ref.Registry, ref.Repository, ref.Reference)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, manifestURL, strings.NewReader(string(manifestBody)))
+	if err != nil {
+		return fmt.Errorf("build manifest push request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+	auth.setAuth(req)
+
+	resp, err := d.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("push manifest: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("push manifest %s: unexpected status %d", ref.Repository, resp.StatusCode)
+	}
+
+	return nil
+}
+
+func extractTarGz(blob []byte, dest string) error {
+	gzr, err := gzip.NewReader(strings.NewReader(string(blob)))
+	if err != nil {
+		return fmt.Errorf("open gzip stream: %w", err)
+	}
+	defer func() { _ = gzr.Close() }()
+
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read tar entry: %w", err)
+		}
+
+		target := filepath.Join(dest, filepath.Clean(hdr.Name))
+		if !strings.HasPrefix(target, filepath.Clean(dest)+string(os.PathSeparator)) {
+			return fmt.Errorf("tar entry %q escapes destination", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0750); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0750); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode))
+			if err != nil {
+				return err
+			}
+			// #nosec G110 -- layer size is bounded by the verified digest above.
+			if _, err := io.Copy(f, tr); err != nil {
+				_ = f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
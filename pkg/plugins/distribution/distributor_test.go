@@ -0,0 +1,74 @@
+package distribution
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigestVerify(t *testing.T) {
+	blob := []byte("plugin-layer-contents")
+	digest := NewDigest(blob)
+
+	require.True(t, digest.Verify(blob))
+	require.False(t, digest.Verify([]byte("tampered")))
+}
+
+func buildTarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name: name,
+			Mode: 0600,
+			Size: int64(len(content)),
+		}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+	return buf.Bytes()
+}
+
+func TestExtractTarGzWritesFiles(t *testing.T) {
+	dest := t.TempDir()
+	blob := buildTarGz(t, map[string]string{
+		"plugin.json":  `{"id":"test"}`,
+		"img/logo.svg": "<svg/>",
+	})
+
+	require.NoError(t, extractTarGz(blob, dest))
+
+	got, err := os.ReadFile(filepath.Join(dest, "plugin.json"))
+	require.NoError(t, err)
+	require.Equal(t, `{"id":"test"}`, string(got))
+
+	got, err = os.ReadFile(filepath.Join(dest, "img", "logo.svg"))
+	require.NoError(t, err)
+	require.Equal(t, "<svg/>", string(got))
+}
+
+func TestExtractTarGzRejectsPathTraversal(t *testing.T) {
+	dest := t.TempDir()
+	blob := buildTarGz(t, map[string]string{
+		"../../etc/passwd": "pwned",
+	})
+
+	err := extractTarGz(blob, dest)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "escapes destination")
+
+	_, statErr := os.Stat(filepath.Join(filepath.Dir(filepath.Dir(dest)), "etc", "passwd"))
+	require.True(t, os.IsNotExist(statErr))
+}
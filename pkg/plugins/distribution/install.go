@@ -0,0 +1,28 @@
+package distribution
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+// Install pulls ref via dist and returns the resulting external Plugin. The
+// plugin's Signature is set to plugins.SignatureContentAddressed and
+// ContentDigest to the digest of the manifest bytes the registry served,
+// rather than relying on a MANIFEST.txt. Pulling the same ref again yields
+// the same ContentDigest only while the registry serves the same manifest
+// for it; a re-pushed tag yields a new one.
+func Install(ctx context.Context, dist Distributor, ref Ref, auth AuthConfig) (*plugins.Plugin, error) {
+	result, err := dist.Pull(ctx, ref, auth)
+	if err != nil {
+		return nil, fmt.Errorf("pull %s: %w", ref.Repository, err)
+	}
+
+	return &plugins.Plugin{
+		JSONData:      result.Config,
+		Class:         plugins.External,
+		Signature:     plugins.SignatureContentAddressed,
+		ContentDigest: string(NewDigest(result.ManifestBytes)),
+	}, nil
+}
@@ -0,0 +1,44 @@
+package distribution
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDistributor struct {
+	result PullResult
+}
+
+func (f *fakeDistributor) Pull(ctx context.Context, ref Ref, auth AuthConfig) (PullResult, error) {
+	return f.result, nil
+}
+
+func (f *fakeDistributor) Push(ctx context.Context, ref Ref, manifest Manifest, auth AuthConfig) error {
+	return nil
+}
+
+func TestInstallDigestsTheRawManifestBytes(t *testing.T) {
+	manifest := Manifest{SchemaVersion: 2, Config: Descriptor{MediaType: MediaTypeConfig, Digest: "sha256:abc", Size: 3}}
+
+	// A real registry response carries fields (mediaType) and formatting that
+	// re-marshaling the decoded Manifest struct won't reproduce byte-for-byte.
+	rawBytes := []byte(`{"mediaType":"application/vnd.oci.image.manifest.v1+json","schemaVersion":2,"config":{"mediaType":"` + MediaTypeConfig + `","digest":"sha256:abc","size":3},"layers":[]}`)
+
+	remarshaled, err := MarshalManifest(manifest)
+	require.NoError(t, err)
+	require.NotEqual(t, rawBytes, remarshaled, "fixture should differ from the re-marshaled struct to prove the two digests would diverge")
+
+	dist := &fakeDistributor{result: PullResult{
+		Manifest:      manifest,
+		ManifestBytes: rawBytes,
+		Config:        plugins.JSONData{ID: "test-datasource"},
+	}}
+
+	plugin, err := Install(context.Background(), dist, Ref{Registry: "registry.example.com", Repository: "grafana-plugins/test-datasource", Reference: "1.0.0"}, AuthConfig{})
+	require.NoError(t, err)
+	require.Equal(t, string(NewDigest(rawBytes)), plugin.ContentDigest)
+	require.NotEqual(t, string(NewDigest(remarshaled)), plugin.ContentDigest)
+}
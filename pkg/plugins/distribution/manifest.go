@@ -0,0 +1,67 @@
+// Package distribution lets plugins be pulled from and pushed to
+// OCI-compatible registries, as an alternative to the signed-zip flow used
+// by the plugin store.
+package distribution
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// MediaTypeConfig is the media type of a plugin's config blob: the
+// JSON-encoded plugins.JSONData plus its declared privileges.
+const MediaTypeConfig = "application/vnd.grafana.plugin.config.v1+json"
+
+// MediaTypeLayer is the media type of a plugin filesystem layer: a gzipped
+// tarball of the plugin's Files.
+const MediaTypeLayer = "application/vnd.grafana.plugin.layer.v1.tar+gzip"
+
+// Digest is a SHA256 content digest in "sha256:<hex>" form, matching the OCI
+// digest format.
+type Digest string
+
+// NewDigest computes the Digest of b.
+func NewDigest(b []byte) Digest {
+	sum := sha256.Sum256(b)
+	return Digest(fmt.Sprintf("sha256:%s", hex.EncodeToString(sum[:])))
+}
+
+// Verify reports whether b hashes to d.
+func (d Digest) Verify(b []byte) bool {
+	return NewDigest(b) == d
+}
+
+// Descriptor references a content-addressed blob within a Manifest, mirroring
+// the OCI image manifest descriptor.
+type Descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    Digest `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// Manifest is modeled on the OCI image manifest v2: a config descriptor
+// pointing at the plugin's JSONData+privileges, and one or more layer
+// descriptors for the plugin filesystem.
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	Config        Descriptor   `json:"config"`
+	Layers        []Descriptor `json:"layers"`
+}
+
+// MarshalManifest encodes m as JSON, the body Push uploads. The result need
+// not match byte-for-byte a manifest a registry served, so ContentDigest is
+// computed from the served bytes (PullResult.ManifestBytes), not from this.
+func MarshalManifest(m Manifest) ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// UnmarshalManifest decodes b into a Manifest.
+func UnmarshalManifest(b []byte) (Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return Manifest{}, fmt.Errorf("unmarshal manifest: %w", err)
+	}
+	return m, nil
+}
@@ -0,0 +1,132 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// EnableConfig configures Plugin.Enable.
+type EnableConfig struct{}
+
+// DisableConfig configures Plugin.Disable.
+type DisableConfig struct {
+	// Reason is recorded alongside the disabled state, e.g. "disabled by
+	// admin" or "disabled: failed health check".
+	Reason string
+
+	// Force disables the plugin even if it has active dependents (such as
+	// datasources configured to use it). Without Force, Disable fails with
+	// ErrPluginHasDependents.
+	Force bool
+}
+
+// ErrPluginHasDependents is returned by Disable when other resources still
+// reference the plugin and DisableConfig.Force was not set.
+type ErrPluginHasDependents struct {
+	PluginID   string
+	Dependents []string
+}
+
+func (e ErrPluginHasDependents) Error() string {
+	return fmt.Sprintf("plugin %s has %d active dependent(s) and was not disabled; pass Force to override", e.PluginID, len(e.Dependents))
+}
+
+// ErrPluginSignatureInvalid is returned by Enable when the plugin's
+// signature failed verification, so a plugin known to be tampered with or
+// unsigned can't be started by enabling it.
+type ErrPluginSignatureInvalid struct {
+	PluginID string
+}
+
+func (e ErrPluginSignatureInvalid) Error() string {
+	return fmt.Sprintf("plugin %s has an invalid signature and cannot be enabled", e.PluginID)
+}
+
+// PluginState is the persisted enable/disable state of an installed plugin.
+type PluginState struct {
+	Enabled        bool
+	LastEnabledAt  time.Time
+	DisabledReason string
+}
+
+// PluginStateStore persists, per plugin ID, whether a plugin is enabled.
+// Enable and Disable write to it; the plugin loader does not read it, so it
+// does not decide which installed plugins are started at boot.
+type PluginStateStore interface {
+	Get(ctx context.Context, pluginID string) (PluginState, error)
+	Set(ctx context.Context, pluginID string, state PluginState) error
+}
+
+// DependentsChecker reports the IDs of resources (e.g. datasources) that
+// currently depend on a plugin, so Disable can refuse to remove a plugin
+// still in use unless forced.
+type DependentsChecker interface {
+	Dependents(ctx context.Context, pluginID string) ([]string, error)
+}
+
+// Enable marks the plugin enabled, persists that state, and starts it. It
+// refuses to enable a plugin that isn't installed, whose signature failed
+// verification, or whose declared privileges haven't been accepted -- the
+// last of those is enforced by Start.
+func (p *Plugin) Enable(ctx context.Context, _ EnableConfig, store PluginStateStore) error {
+	if p.Files == nil {
+		return fmt.Errorf("plugin %s is not installed", p.ID)
+	}
+	if p.SignatureError != nil {
+		return ErrPluginSignatureInvalid{PluginID: p.ID}
+	}
+
+	if err := p.Start(ctx); err != nil {
+		return err
+	}
+
+	p.enabled = true
+	if store != nil {
+		if err := store.Set(ctx, p.ID, PluginState{Enabled: true, LastEnabledAt: time.Now()}); err != nil {
+			return fmt.Errorf("persist enabled state for %s: %w", p.ID, err)
+		}
+	}
+	return nil
+}
+
+// Disable stops the plugin and marks it so it won't be auto-started on the
+// next Grafana boot. If checker reports active dependents, Disable fails
+// with ErrPluginHasDependents unless cfg.Force is set.
+func (p *Plugin) Disable(ctx context.Context, cfg DisableConfig, store PluginStateStore, checker DependentsChecker) error {
+	if !cfg.Force && checker != nil {
+		dependents, err := checker.Dependents(ctx, p.ID)
+		if err != nil {
+			return fmt.Errorf("check dependents for %s: %w", p.ID, err)
+		}
+		if len(dependents) > 0 {
+			return ErrPluginHasDependents{PluginID: p.ID, Dependents: dependents}
+		}
+	}
+
+	if err := p.Stop(ctx); err != nil {
+		return err
+	}
+
+	p.enabled = false
+	if store != nil {
+		if err := store.Set(ctx, p.ID, PluginState{Enabled: false, DisabledReason: cfg.Reason}); err != nil {
+			return fmt.Errorf("persist disabled state for %s: %w", p.ID, err)
+		}
+	}
+	return nil
+}
+
+// IsEnabled reports the plugin's last-known enable/disable state, as set by
+// Enable, Disable, or SetEnabled (e.g. when hydrating from a
+// PluginStateStore at boot).
+func (p *Plugin) IsEnabled() bool {
+	return p.enabled
+}
+
+// SetEnabled hydrates the plugin's enabled state without going through the
+// Start/Stop side effects of Enable/Disable, for use when loading persisted
+// state at boot.
+func (p *Plugin) SetEnabled(enabled bool) {
+	p.enabled = enabled
+}
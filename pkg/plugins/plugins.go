@@ -25,6 +25,12 @@ type Plugin struct {
 	Files     FS
 	Class     Class
 
+	// Alias is the install identity this plugin was registered under, set
+	// when installed with InstallOpts.Alias so the same JSONData.ID can be
+	// installed more than once side-by-side. Empty unless an alias was
+	// requested at install time.
+	Alias string
+
 	// App fields
 	IncludedInAppID string
 	DefaultNavURL   string
@@ -39,6 +45,12 @@ type Plugin struct {
 	SignedFiles    PluginFiles
 	SignatureError *SignatureError
 
+	// ContentDigest is the SHA256 digest of the OCI manifest this plugin was
+	// pulled from, set when the plugin was installed via the distribution
+	// package rather than a signed zip. It is empty for plugins installed
+	// through the legacy flow.
+	ContentDigest string
+
 	// SystemJS fields
 	Module  string
 	BaseURL string
@@ -47,6 +59,70 @@ type Plugin struct {
 	SecretsManager secretsmanagerplugin.SecretsManagerPlugin
 	client         backendplugin.Plugin
 	log            log.Logger
+
+	acceptedPrivileges PluginPrivileges
+	enabled            bool
+
+	// MaxConcurrentStreams bounds how many logical streams the multiplexed
+	// RPC connection (see pkg/plugins/backendplugin.Session) holds open with
+	// this plugin at once; opening a stream beyond it fails. Only
+	// CallResource and RunStream use streams. Zero means the backendplugin
+	// default.
+	MaxConcurrentStreams int
+
+	// muxSession is non-nil when RegisterClient negotiated a multiplexed
+	// connection with this plugin (see backendplugin.NegotiateMux). When
+	// set, CallResource and RunStream each reserve their own stream for the
+	// duration of the call so one can't be head-of-line-blocked behind the
+	// other or behind a large QueryData response.
+	muxSession *backendplugin.Session
+}
+
+// muxTransport is implemented by a backendplugin.Plugin whose underlying
+// connection can be multiplexed. RegisterClient negotiates a Session over it
+// when present; plugins that don't implement it keep using the existing
+// single-stream behavior.
+type muxTransport interface {
+	MuxTransport() io.ReadWriteCloser
+}
+
+// resourceStreamer is implemented by a backendplugin.Plugin that can drive a
+// CallResource or RunStream call over a caller-supplied stream instead of its
+// default transport. withResourceStream uses this to actually put the call's
+// request/response bytes on the reserved mux Stream -- without it, reserving
+// a stream and never touching it would isolate nothing. Plugins that only
+// implement muxTransport (mux negotiated) but not resourceStreamer still have
+// a stream reserved per call, which counts against MaxConcurrentStreams, but
+// the call's bytes go over the client's default transport.
+type resourceStreamer interface {
+	CallResourceOverStream(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender, stream io.ReadWriteCloser) error
+	RunStreamOverStream(ctx context.Context, req *backend.RunStreamRequest, sender *backend.StreamSender, stream io.ReadWriteCloser) error
+}
+
+// withResourceStream reserves a muxed stream for the duration of fn, when the
+// plugin negotiated a mux session, so the call is isolated from and can't be
+// starved by other concurrent calls on the same connection. fn is handed the
+// reserved stream (nil with no mux session) so it can actually route the
+// call's I/O over it when the client supports that.
+func (p *Plugin) withResourceStream(fn func(stream io.ReadWriteCloser) error) error {
+	if p.muxSession == nil {
+		return fn(nil)
+	}
+
+	stream, err := p.muxSession.Open()
+	if err != nil {
+		return fmt.Errorf("open mux stream for plugin %s: %w", p.ID, err)
+	}
+	defer func() { _ = stream.Close() }()
+
+	return fn(stream)
+}
+
+// SetAcceptedPrivileges records the privileges that were last accepted for
+// this plugin, typically loaded from a PluginPrivilegesStore at boot. Start
+// refuses to run the plugin if its declared privileges exceed this set.
+func (p *Plugin) SetAcceptedPrivileges(privileges PluginPrivileges) {
+	p.acceptedPrivileges = privileges
 }
 
 type PluginDTO struct {
@@ -56,6 +132,10 @@ type PluginDTO struct {
 
 	class Class
 
+	// Alias mirrors Plugin.Alias: the install identity this plugin was
+	// registered under, if different from JSONData.ID.
+	Alias string
+
 	// App fields
 	IncludedInAppID string
 	DefaultNavURL   string
@@ -167,6 +247,9 @@ type JSONData struct {
 	// AccessControl settings
 	Roles []RoleRegistration `json:"roles,omitempty"`
 
+	// Privileges settings
+	Privileges []Privilege `json:"privileges,omitempty"`
+
 	// Panel settings
 	SkipDataQuery bool `json:"skipDataQuery"`
 
@@ -240,6 +323,18 @@ type JWTTokenAuth struct {
 }
 
 func (p *Plugin) PluginID() string {
+	return p.InstallName()
+}
+
+// InstallName returns the identity this plugin was installed under: its
+// Alias if one was requested at install time, otherwise its JSONData.ID.
+// PluginID and StaticRoute key off this rather than ID so that the same
+// plugin can be installed multiple times under different aliases.
+// ExecutablePath does not: it resolves the binary from the Executable name.
+func (p *Plugin) InstallName() string {
+	if p.Alias != "" {
+		return p.Alias
+	}
 	return p.ID
 }
 
@@ -251,10 +346,19 @@ func (p *Plugin) SetLogger(l log.Logger) {
 	p.log = l
 }
 
+// Privileges returns the set of privileges this plugin declares it needs at
+// runtime, as read from plugin.json.
+func (p *Plugin) Privileges() PluginPrivileges {
+	return PluginPrivileges(p.JSONData.Privileges)
+}
+
 func (p *Plugin) Start(ctx context.Context) error {
 	if p.client == nil {
 		return fmt.Errorf("could not start plugin %s as no plugin client exists", p.ID)
 	}
+	if !p.acceptedPrivileges.accepts(p.Privileges()) {
+		return ErrPrivilegesNotAccepted{PluginID: p.ID}
+	}
 	return p.client.Start(ctx)
 }
 
@@ -306,7 +410,12 @@ func (p *Plugin) CallResource(ctx context.Context, req *backend.CallResourceRequ
 	if !ok {
 		return backendplugin.ErrPluginUnavailable
 	}
-	return pluginClient.CallResource(ctx, req, sender)
+	return p.withResourceStream(func(stream io.ReadWriteCloser) error {
+		if streamer, ok := pluginClient.(resourceStreamer); ok && stream != nil {
+			return streamer.CallResourceOverStream(ctx, req, sender, stream)
+		}
+		return pluginClient.CallResource(ctx, req, sender)
+	})
 }
 
 func (p *Plugin) CheckHealth(ctx context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
@@ -346,11 +455,23 @@ func (p *Plugin) RunStream(ctx context.Context, req *backend.RunStreamRequest, s
 	if !ok {
 		return backendplugin.ErrPluginUnavailable
 	}
-	return pluginClient.RunStream(ctx, req, sender)
+	return p.withResourceStream(func(stream io.ReadWriteCloser) error {
+		if streamer, ok := pluginClient.(resourceStreamer); ok && stream != nil {
+			return streamer.RunStreamOverStream(ctx, req, sender, stream)
+		}
+		return pluginClient.RunStream(ctx, req, sender)
+	})
 }
 
 func (p *Plugin) RegisterClient(c backendplugin.Plugin) {
 	p.client = c
+
+	if mt, ok := c.(muxTransport); ok {
+		session, err := backendplugin.NegotiateMux(mt.MuxTransport(), true, p.MaxConcurrentStreams)
+		if err == nil {
+			p.muxSession = session
+		}
+	}
 }
 
 func (p *Plugin) Client() (PluginClient, bool) {
@@ -399,6 +520,7 @@ func (p *Plugin) ToDTO() PluginDTO {
 	return PluginDTO{
 		files:             p.Files,
 		class:             p.Class,
+		Alias:             p.Alias,
 		signedFiles:       p.SignedFiles,
 		supportsStreaming: p.client != nil && p.client.(backend.StreamHandler) != nil,
 		JSONData:          p.JSONData,
@@ -418,7 +540,7 @@ func (p *Plugin) StaticRoute() *StaticRoute {
 		return nil
 	}
 
-	return &StaticRoute{Directory: p.Files.Base(), PluginID: p.ID}
+	return &StaticRoute{Directory: p.Files.Base(), PluginID: p.InstallName()}
 }
 
 func (p *Plugin) IsRenderer() bool {
@@ -466,6 +588,47 @@ func (p *Plugin) Manifest() []byte {
 	return m
 }
 
+// SignatureContentAddressed indicates that a plugin's integrity is backed by
+// a content-addressable digest chain (see pkg/plugins/distribution) rather
+// than a MANIFEST.txt signature block. Plugins in this state carry a
+// populated ContentDigest and can be verified without re-hashing MANIFEST.txt.
+const SignatureContentAddressed SignatureStatus = "contentAddressed"
+
+// InstallOpts configures how a plugin is installed. An empty InstallOpts
+// installs the plugin under its own JSONData.ID, as before.
+type InstallOpts struct {
+	// Alias, if set, registers the plugin under this install identity
+	// instead of JSONData.ID, allowing the same plugin to be installed
+	// more than once side-by-side (e.g. to A/B test two datasource
+	// versions against the same Grafana instance).
+	Alias string
+}
+
+// ErrAliasAlreadyExists is returned when an InstallOpts.Alias collides with
+// the ID or InstallName of an already-installed plugin.
+type ErrAliasAlreadyExists struct {
+	Alias string
+}
+
+func (e ErrAliasAlreadyExists) Error() string {
+	return fmt.Sprintf("plugin alias %q is already in use by an installed plugin", e.Alias)
+}
+
+// ValidateAlias reports whether alias may be used as an install identity
+// given the set of already-installed plugins, rejecting collisions with any
+// existing plugin's ID or InstallName.
+func ValidateAlias(alias string, installed []*Plugin) error {
+	if alias == "" {
+		return nil
+	}
+	for _, p := range installed {
+		if p.ID == alias || p.InstallName() == alias {
+			return ErrAliasAlreadyExists{Alias: alias}
+		}
+	}
+	return nil
+}
+
 type Class string
 
 const (
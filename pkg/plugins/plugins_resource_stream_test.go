@@ -0,0 +1,126 @@
+package plugins
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana/pkg/plugins/backendplugin"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMuxClient is a minimal backendplugin.Plugin that negotiates a mux
+// session over a net.Conn and drives CallResource over the reserved stream
+// (RunStreamOverStream is a no-op), so TestCallResourceRoutesOverMuxStream
+// can assert that bytes actually cross the mux Stream rather than just
+// reserving one.
+type fakeMuxClient struct {
+	conn net.Conn
+}
+
+func (f *fakeMuxClient) MuxTransport() io.ReadWriteCloser { return f.conn }
+
+func (f *fakeMuxClient) CallResourceOverStream(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender, stream io.ReadWriteCloser) error {
+	payload := []byte(req.Path)
+	if _, err := stream.Write(payload); err != nil {
+		return err
+	}
+	echoed := make([]byte, len(payload))
+	if _, err := io.ReadFull(stream, echoed); err != nil {
+		return err
+	}
+	return sender.Send(&backend.CallResourceResponse{Status: 200, Body: echoed})
+}
+
+func (f *fakeMuxClient) RunStreamOverStream(ctx context.Context, req *backend.RunStreamRequest, sender *backend.StreamSender, stream io.ReadWriteCloser) error {
+	return nil
+}
+
+func (f *fakeMuxClient) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	return nil, nil
+}
+func (f *fakeMuxClient) CallResource(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	return nil
+}
+func (f *fakeMuxClient) CheckHealth(ctx context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
+	return nil, nil
+}
+func (f *fakeMuxClient) CollectMetrics(ctx context.Context, req *backend.CollectMetricsRequest) (*backend.CollectMetricsResult, error) {
+	return nil, nil
+}
+func (f *fakeMuxClient) SubscribeStream(ctx context.Context, req *backend.SubscribeStreamRequest) (*backend.SubscribeStreamResponse, error) {
+	return nil, nil
+}
+func (f *fakeMuxClient) PublishStream(ctx context.Context, req *backend.PublishStreamRequest) (*backend.PublishStreamResponse, error) {
+	return nil, nil
+}
+func (f *fakeMuxClient) RunStream(ctx context.Context, req *backend.RunStreamRequest, sender *backend.StreamSender) error {
+	return nil
+}
+func (f *fakeMuxClient) Start(ctx context.Context) error { return nil }
+func (f *fakeMuxClient) Stop(ctx context.Context) error  { return nil }
+func (f *fakeMuxClient) IsManaged() bool                 { return false }
+func (f *fakeMuxClient) Decommission() error             { return nil }
+func (f *fakeMuxClient) IsDecommissioned() bool          { return false }
+func (f *fakeMuxClient) Exited() bool                    { return false }
+
+type fakeResourceSender struct {
+	responses []*backend.CallResourceResponse
+}
+
+func (f *fakeResourceSender) Send(resp *backend.CallResourceResponse) error {
+	f.responses = append(f.responses, resp)
+	return nil
+}
+
+// TestCallResourceRoutesOverMuxStream guards against withResourceStream
+// reserving a mux Stream and then calling straight through to the client's
+// default transport: it wires a real Session on each end of a net.Conn pair,
+// has the peer echo whatever it reads off the accepted stream, and asserts
+// CallResource's response body is that echo -- which is only possible if the
+// request actually traveled over the mux Stream.
+func TestCallResourceRoutesOverMuxStream(t *testing.T) {
+	connA, connB := net.Pipe()
+
+	type negotiateResult struct {
+		session *backendplugin.Session
+		err     error
+	}
+	serverNegotiated := make(chan negotiateResult, 1)
+	go func() {
+		s, err := backendplugin.NegotiateMux(connB, false, 4)
+		serverNegotiated <- negotiateResult{s, err}
+	}()
+
+	client := &fakeMuxClient{conn: connA}
+	p := &Plugin{JSONData: JSONData{ID: "test-datasource"}, MaxConcurrentStreams: 4}
+	p.RegisterClient(client)
+	require.NotNil(t, p.muxSession)
+
+	result := <-serverNegotiated
+	require.NoError(t, result.err)
+	require.NotNil(t, result.session)
+	server := result.session
+
+	echoDone := make(chan struct{})
+	go func() {
+		defer close(echoDone)
+		stream, err := server.Accept()
+		require.NoError(t, err)
+		buf := make([]byte, len("/query"))
+		_, err = io.ReadFull(stream, buf)
+		require.NoError(t, err)
+		_, err = stream.Write(buf)
+		require.NoError(t, err)
+	}()
+
+	sender := &fakeResourceSender{}
+	err := p.CallResource(context.Background(), &backend.CallResourceRequest{Path: "/query"}, sender)
+	require.NoError(t, err)
+
+	<-echoDone
+	require.Len(t, sender.responses, 1)
+	require.Equal(t, []byte("/query"), sender.responses[0].Body)
+}
@@ -0,0 +1,131 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Privilege describes a single capability a plugin requires at runtime, such
+// as network access or a mounted host path. It is declared in plugin.json
+// and must be explicitly accepted before the plugin is installed or enabled.
+type Privilege struct {
+	// Name identifies the kind of privilege, e.g. "network", "mount",
+	// "capabilities", "device", "allow-host-access".
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Value       []string `json:"value"`
+}
+
+// PluginPrivileges is the set of privileges a plugin declares, or that a
+// user has accepted for it.
+type PluginPrivileges []Privilege
+
+// accepts reports whether every privilege in want is present, by Name and
+// Value, in the accepted set p. A nil or empty want always accepts.
+func (p PluginPrivileges) accepts(want PluginPrivileges) bool {
+	for _, w := range want {
+		if !p.contains(w) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p PluginPrivileges) contains(want Privilege) bool {
+	for _, have := range p {
+		if have.Name != want.Name || len(have.Value) != len(want.Value) {
+			continue
+		}
+		match := true
+		for i := range have.Value {
+			if have.Value[i] != want.Value[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff returns the privileges in want that are not already present in p,
+// i.e. the set that must be freshly accepted before want can be granted.
+func (p PluginPrivileges) Diff(want PluginPrivileges) PluginPrivileges {
+	var added PluginPrivileges
+	for _, w := range want {
+		if !p.contains(w) {
+			added = append(added, w)
+		}
+	}
+	return added
+}
+
+// ErrPrivilegesNotAccepted is returned by Plugin.Start when the plugin's
+// declared privileges have not been accepted, or have grown since they were
+// last accepted (e.g. after an upgrade).
+type ErrPrivilegesNotAccepted struct {
+	PluginID string
+}
+
+func (e ErrPrivilegesNotAccepted) Error() string {
+	return fmt.Sprintf("plugin %s declares privileges that have not been accepted", e.PluginID)
+}
+
+// PrivilegesFetcher can resolve the privileges a plugin would require before
+// it is installed, so that install tooling can present them for consent
+// ahead of pulling and running the plugin.
+type PrivilegesFetcher interface {
+	GetPrivileges(ctx context.Context, ref string) (PluginPrivileges, error)
+}
+
+// PluginPrivilegesStore persists, per plugin ID, the set of privileges a
+// user has accepted. Install/upgrade flows diff a plugin's declared
+// privileges against the stored set and require re-acceptance when it grows.
+type PluginPrivilegesStore interface {
+	Get(ctx context.Context, pluginID string) (PluginPrivileges, error)
+	Set(ctx context.Context, pluginID string, privileges PluginPrivileges) error
+}
+
+// MemoryPrivilegesStore is an in-memory PluginPrivilegesStore. Accepted
+// privileges it holds last only for the life of the process.
+type MemoryPrivilegesStore struct {
+	mu       sync.Mutex
+	accepted map[string]PluginPrivileges
+}
+
+// NewMemoryPrivilegesStore returns an empty MemoryPrivilegesStore.
+func NewMemoryPrivilegesStore() *MemoryPrivilegesStore {
+	return &MemoryPrivilegesStore{accepted: make(map[string]PluginPrivileges)}
+}
+
+func (s *MemoryPrivilegesStore) Get(_ context.Context, pluginID string) (PluginPrivileges, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accepted[pluginID], nil
+}
+
+func (s *MemoryPrivilegesStore) Set(_ context.Context, pluginID string, privileges PluginPrivileges) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accepted[pluginID] = privileges
+	return nil
+}
+
+// HydrateAcceptedPrivileges loads each installed plugin's previously
+// accepted privileges from store and applies them via
+// Plugin.SetAcceptedPrivileges, so Start's privilege check compares against
+// the stored set. The plugin loader does not call it; a plugin whose accepted
+// set was never applied is refused by Start if it declares any privilege.
+func HydrateAcceptedPrivileges(ctx context.Context, store PluginPrivilegesStore, installed []*Plugin) error {
+	for _, p := range installed {
+		accepted, err := store.Get(ctx, p.ID)
+		if err != nil {
+			return fmt.Errorf("load accepted privileges for %s: %w", p.ID, err)
+		}
+		p.SetAcceptedPrivileges(accepted)
+	}
+	return nil
+}
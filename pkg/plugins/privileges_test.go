@@ -0,0 +1,32 @@
+package plugins
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPluginPrivilegesAccepts(t *testing.T) {
+	accepted := PluginPrivileges{
+		{Name: "network", Value: []string{"example.com"}},
+	}
+
+	require.True(t, accepted.accepts(nil))
+	require.True(t, accepted.accepts(PluginPrivileges{{Name: "network", Value: []string{"example.com"}}}))
+	require.False(t, accepted.accepts(PluginPrivileges{{Name: "mount", Value: []string{"/data"}}}))
+	require.False(t, accepted.accepts(PluginPrivileges{{Name: "network", Value: []string{"other.com"}}}))
+}
+
+func TestPluginPrivilegesDiff(t *testing.T) {
+	accepted := PluginPrivileges{
+		{Name: "network", Value: []string{"example.com"}},
+	}
+	want := PluginPrivileges{
+		{Name: "network", Value: []string{"example.com"}},
+		{Name: "mount", Value: []string{"/data"}},
+	}
+
+	diff := accepted.Diff(want)
+	require.Equal(t, PluginPrivileges{{Name: "mount", Value: []string{"/data"}}}, diff)
+	require.Empty(t, accepted.Diff(accepted))
+}